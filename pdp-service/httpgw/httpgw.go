@@ -0,0 +1,121 @@
+// Package httpgw exposes the PDP's gRPC Validate call as a plain HTTP/JSON endpoint, the same way
+// a gRPC-gateway reverse proxy exposes a gRPC service to browsers and curl. It reuses
+// pepcli/requests to parse the request body, so the accepted body shape is exactly the YAML/JSON
+// (and CBOR, via Content-Type) that pepcli itself reads from a file.
+package httpgw
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/infobloxopen/themis/pdp"
+	pb "github.com/infobloxopen/themis/pdp-service"
+	"github.com/infobloxopen/themis/pepcli/requests"
+)
+
+// Handler serves a single HTTP/JSON endpoint backed by a PDP gRPC client. Create one with
+// NewHandler and mount it with http.Handle/http.ServeMux.
+type Handler struct {
+	Client pb.PDPClient
+}
+
+// NewHandler creates a Handler that validates requests against the PDP reachable through client.
+func NewHandler(client pb.PDPClient) *Handler {
+	return &Handler{Client: client}
+}
+
+// response is the JSON shape returned for a decision: the PDP effect by name, the obligations
+// attached to it, and, on failure, a human-readable reason.
+type response struct {
+	Effect      string                 `json:"effect"`
+	Reason      string                 `json:"reason,omitempty"`
+	Obligations map[string]interface{} `json:"obligations,omitempty"`
+}
+
+// ServeHTTP decodes a YAML/JSON/CBOR request body with the same shape pepcli reads from a file,
+// forwards it to the PDP over gRPC, and writes the decision back as JSON.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msgs, err := requests.LoadReader(r.Body, bodyFormat(r), 0)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("can't parse request body: %s", err), http.StatusBadRequest)
+		return
+	}
+	if len(msgs) != 1 {
+		http.Error(w, fmt.Sprintf("expected exactly one request, got %d", len(msgs)), http.StatusBadRequest)
+		return
+	}
+
+	out, err := h.Client.Validate(r.Context(), &msgs[0])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("PDP request failed: %s", err), http.StatusBadGateway)
+		return
+	}
+
+	res, err := marshalResponse(out)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("can't parse PDP response: %s", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// bodyFormat picks the requests package format constant matching the request's Content-Type,
+// defaulting to JSON for anything unrecognized (curl without an explicit header, most notably).
+func bodyFormat(r *http.Request) string {
+	ct := strings.ToLower(r.Header.Get("Content-Type"))
+	switch {
+	case strings.Contains(ct, "yaml"):
+		return requests.YAML
+	case strings.Contains(ct, "cbor"):
+		return requests.CBOR
+	default:
+		return requests.JSON
+	}
+}
+
+// marshalResponse unpacks a PDP decision message into the JSON shape ServeHTTP writes back.
+func marshalResponse(msg *pb.Msg) (response, error) {
+	effect, obligations, err := pdp.UnmarshalResponseAssignments(msg.Body)
+	if err != nil {
+		return response{}, err
+	}
+
+	return obligationsToResponse(pdp.EffectNameFromEnum(effect), obligations)
+}
+
+// obligationsToResponse builds the JSON response body from an already-named effect and a
+// decision's obligations, pulling the PDP's "reason" obligation (if present) out to its own field
+// instead of leaving it mixed in with the rest. Split out from marshalResponse so the response
+// shaping itself--as opposed to the wire decode--can be exercised directly in tests.
+func obligationsToResponse(effectName string, obligations []pdp.AttributeAssignment) (response, error) {
+	res := response{Effect: effectName}
+
+	if len(obligations) > 0 {
+		res.Obligations = make(map[string]interface{}, len(obligations))
+		for _, o := range obligations {
+			s, err := o.Serialize()
+			if err != nil {
+				return response{}, fmt.Errorf("can't serialize %q obligation: %s", o.GetID(), err)
+			}
+
+			if o.GetID() == "reason" {
+				res.Reason = s
+				continue
+			}
+
+			res.Obligations[o.GetID()] = s
+		}
+	}
+
+	return res, nil
+}
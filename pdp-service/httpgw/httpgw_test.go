@@ -0,0 +1,132 @@
+package httpgw
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/infobloxopen/themis/pdp"
+	pb "github.com/infobloxopen/themis/pdp-service"
+)
+
+var errValidateFailed = errors.New("validate: connection refused")
+
+// stubPDPClient stands in for a real PDP connection in these tests--it's handed the exact
+// request Msg the Handler built and returns a canned response, so ServeHTTP can be exercised
+// end to end (parse body -> "call" the PDP -> write the decision as JSON) without a running PDP.
+type stubPDPClient struct {
+	pb.PDPClient
+
+	resp *pb.Msg
+	err  error
+	got  *pb.Msg
+}
+
+func (c *stubPDPClient) Validate(ctx context.Context, in *pb.Msg, opts ...grpc.CallOption) (*pb.Msg, error) {
+	c.got = in
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.resp, nil
+}
+
+func TestServeHTTPRejectsNonPost(t *testing.T) {
+	h := NewHandler(&stubPDPClient{})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != 405 {
+		t.Fatalf("expected 405 Method Not Allowed for GET, got %d", w.Code)
+	}
+}
+
+func TestServeHTTPRejectsMultipleRequests(t *testing.T) {
+	h := NewHandler(&stubPDPClient{})
+
+	body := `{"attributes": {}, "requests": [{"a": "x"}, {"a": "y"}]}`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != 400 {
+		t.Fatalf("expected 400 Bad Request for a body with more than one request, got %d", w.Code)
+	}
+}
+
+func TestServeHTTPRejectsMalformedBody(t *testing.T) {
+	h := NewHandler(&stubPDPClient{})
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader("not json"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != 400 {
+		t.Fatalf("expected 400 Bad Request for an unparseable body, got %d", w.Code)
+	}
+}
+
+func TestServeHTTPForwardsPDPFailure(t *testing.T) {
+	h := NewHandler(&stubPDPClient{err: errValidateFailed})
+
+	body := `{"attributes": {}, "requests": [{"a": "x"}]}`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != 502 {
+		t.Fatalf("expected 502 Bad Gateway when the PDP call fails, got %d", w.Code)
+	}
+}
+
+func TestObligationsToResponseSeparatesReason(t *testing.T) {
+	obligations := []pdp.AttributeAssignment{
+		pdp.MakeExpressionAssignment("reason", pdp.MakeStringValue("no matching policy")),
+		pdp.MakeExpressionAssignment("log-id", pdp.MakeStringValue("abc123")),
+	}
+
+	res, err := obligationsToResponse("Deny", obligations)
+	if err != nil {
+		t.Fatalf("obligationsToResponse: %s", err)
+	}
+
+	if res.Effect != "Deny" {
+		t.Errorf("Effect = %q, want %q", res.Effect, "Deny")
+	}
+	if res.Reason != "no matching policy" {
+		t.Errorf(`Reason = %q, want the "reason" obligation's value`, res.Reason)
+	}
+	if got := res.Obligations["log-id"]; got != "abc123" {
+		t.Errorf(`Obligations["log-id"] = %v, want "abc123"`, got)
+	}
+	if _, ok := res.Obligations["reason"]; ok {
+		t.Error(`"reason" should be pulled out into Reason, not left in Obligations`)
+	}
+}
+
+func TestBodyFormat(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"application/yaml", "yaml"},
+		{"application/cbor", "cbor"},
+		{"application/json", "json"},
+		{"", "json"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Content-Type", tt.contentType)
+
+		if got := bodyFormat(r); got != tt.want {
+			t.Errorf("bodyFormat(Content-Type: %q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
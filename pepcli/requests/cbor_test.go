@@ -0,0 +1,71 @@
+package requests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fxamacker/cbor"
+)
+
+func TestLoadCBORFile(t *testing.T) {
+	in := requests{
+		Attributes: map[string]string{"d": "domain"},
+		Requests:   []map[string]interface{}{{"d": "example.com"}},
+	}
+
+	b, err := cbor.Marshal(in, cbor.EncOptions{})
+	if err != nil {
+		t.Fatalf("cbor.Marshal: %s", err)
+	}
+
+	f, err := ioutil.TempFile("", "requests-*.cbor")
+	if err != nil {
+		t.Fatalf("TempFile: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	msgs, err := Load(f.Name(), 0)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(msgs))
+	}
+}
+
+func TestLoadReaderCBOR(t *testing.T) {
+	in := requests{
+		Attributes: map[string]string{},
+		Requests:   []map[string]interface{}{{"a": "x"}, {"a": "y"}},
+	}
+
+	b, err := cbor.Marshal(in, cbor.EncOptions{})
+	if err != nil {
+		t.Fatalf("cbor.Marshal: %s", err)
+	}
+
+	msgs, err := LoadReader(bytes.NewReader(b), CBOR, 0)
+	if err != nil {
+		t.Fatalf("LoadReader: %s", err)
+	}
+
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(msgs))
+	}
+}
+
+func TestLoadUnknownCBORBytesFails(t *testing.T) {
+	if _, err := LoadReader(bytes.NewReader([]byte{0xff, 0xff, 0xff}), CBOR, 0); err == nil {
+		t.Fatal("expected an error decoding garbage CBOR bytes")
+	}
+}
@@ -0,0 +1,141 @@
+package requests
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/infobloxopen/themis/pdp"
+)
+
+func TestDecoderJSONAttributesAfterRequests(t *testing.T) {
+	// "requests" appears before "attributes" in source order, which JSON doesn't guarantee one
+	// way or the other--the Decoder must still pick up the declared type.
+	const doc = `{
+		"requests": [{"d": "example.com"}],
+		"attributes": {"d": "domain"}
+	}`
+
+	d, err := NewDecoder(strings.NewReader(doc), JSON)
+	if err != nil {
+		t.Fatalf("NewDecoder: %s", err)
+	}
+
+	if typ, ok := d.symbols["d"]; !ok || typ != pdp.TypeDomain {
+		t.Fatalf("expected \"d\" to resolve to TypeDomain, got %v (declared: %v)", typ, ok)
+	}
+}
+
+func TestDecoderJSONSkipsMalformedRequest(t *testing.T) {
+	// The second request's attribute is a shape makeAttribute can't marshal. That must not be
+	// returned twice, nor stall the stream before the well-formed record after it is reached and
+	// io.EOF is finally returned.
+	const doc = `{
+		"attributes": {},
+		"requests": [{"a": "x"}, {"a": {"nested": true}}, {"a": "y"}]
+	}`
+
+	d, err := NewDecoder(strings.NewReader(doc), JSON)
+	if err != nil {
+		t.Fatalf("NewDecoder: %s", err)
+	}
+
+	var results []error
+	for i := 0; i < 4; i++ {
+		_, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+
+		results = append(results, err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results before io.EOF (ok, error, ok), got %d: %v", len(results), results)
+	}
+
+	if results[0] != nil {
+		t.Errorf("expected first request to decode cleanly, got %s", results[0])
+	}
+	if results[1] == nil {
+		t.Errorf("expected an error for the malformed second request")
+	}
+	if results[2] != nil {
+		t.Errorf("expected third request to decode cleanly, got %s", results[2])
+	}
+}
+
+func TestDecoderJSONSyntaxErrorTerminatesStream(t *testing.T) {
+	// The second array element isn't even syntactically valid JSON. encoding/json can't resync a
+	// token stream after that, so Next must report it once and then stop--not hang retrying the
+	// same bytes and the same error forever.
+	const doc = `{
+		"attributes": {},
+		"requests": [{"a": "x"}, {"a": !!!}, {"a": "y"}]
+	}`
+
+	d, err := NewDecoder(strings.NewReader(doc), JSON)
+	if err != nil {
+		t.Fatalf("NewDecoder: %s", err)
+	}
+
+	if _, err := d.Next(); err != nil {
+		t.Fatalf("expected the first request to decode cleanly, got %s", err)
+	}
+
+	if _, err := d.Next(); err == nil {
+		t.Fatal("expected an error for the syntactically invalid second request")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := d.Next(); err != io.EOF {
+			t.Fatalf("expected io.EOF after the fatal syntax error, got %v", err)
+		}
+	}
+}
+
+func TestDecoderJSONStreamsWithoutBufferingRequests(t *testing.T) {
+	// With "attributes" first, enterJSON must leave dec positioned inside the array instead of
+	// buffering it--this is the whole point of chunk0-2's streaming Decoder.
+	const doc = `{"attributes": {}, "requests": [{"a": "x"}, {"a": "y"}]}`
+
+	d, err := NewDecoder(strings.NewReader(doc), JSON)
+	if err != nil {
+		t.Fatalf("NewDecoder: %s", err)
+	}
+
+	if d.jDec == nil {
+		t.Fatal("expected the Decoder to stream directly from the source when attributes precedes requests")
+	}
+	if len(d.jReqs) != 0 {
+		t.Fatalf("expected no buffered requests, got %d", len(d.jReqs))
+	}
+}
+
+func TestDecoderJSONWithSchema(t *testing.T) {
+	const doc = `{"attributes": {}, "requests": [{"d": "example.com"}]}`
+
+	d, err := NewDecoder(strings.NewReader(doc), JSON, WithSchema(map[string]pdp.Type{"d": pdp.TypeDomain}))
+	if err != nil {
+		t.Fatalf("NewDecoder: %s", err)
+	}
+
+	if typ, ok := d.symbols["d"]; !ok || typ != pdp.TypeDomain {
+		t.Fatalf("expected \"d\" to resolve to TypeDomain from the schema, got %v (declared: %v)", typ, ok)
+	}
+}
+
+func TestDecoderJSONFileAttributesOverrideSchema(t *testing.T) {
+	// An attribute declared in the file's own "attributes" section takes precedence over the
+	// schema, same as WithSchema documents for Load/LoadReader.
+	const doc = `{"attributes": {"d": "string"}, "requests": [{"d": "not-a-domain-but-a-string"}]}`
+
+	d, err := NewDecoder(strings.NewReader(doc), JSON, WithSchema(map[string]pdp.Type{"d": pdp.TypeDomain}))
+	if err != nil {
+		t.Fatalf("NewDecoder: %s", err)
+	}
+
+	if typ, ok := d.symbols["d"]; !ok || typ != pdp.TypeString {
+		t.Fatalf("expected the file's own declaration to win over the schema, got %v (declared: %v)", typ, ok)
+	}
+}
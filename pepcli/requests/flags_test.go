@@ -0,0 +1,81 @@
+package requests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/infobloxopen/themis/pdp"
+)
+
+func TestFlagBitFromName(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		want    int
+		wantErr bool
+	}{
+		{name: "0", bits: 8, want: 0},
+		{name: "bit5", bits: 8, want: 5},
+		{name: "BIT7", bits: 8, want: 7},
+		{name: "not-a-bit", bits: 8, wantErr: true},
+		{name: "8", bits: 8, wantErr: true},  // out of range for an 8-bit flags type
+		{name: "-1", bits: 8, wantErr: true}, // negative
+	}
+
+	for _, tt := range tests {
+		got, err := flagBitFromName(tt.name, tt.bits)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("flagBitFromName(%q, %d) = %d, nil; want error", tt.name, tt.bits, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("flagBitFromName(%q, %d) unexpected error: %s", tt.name, tt.bits, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("flagBitFromName(%q, %d) = %d, want %d", tt.name, tt.bits, got, tt.want)
+		}
+	}
+}
+
+func TestFlags8MarshallerBitmaskAndNames(t *testing.T) {
+	m := builtinMarshallers[pdp.TypeFlags8]
+
+	want := pdp.MakeFlags8Value(5) // 0b101: bits 0 and 2 set
+
+	bitmask, err := m(uint64(5))
+	if err != nil {
+		t.Fatalf("bitmask form: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(bitmask, want) {
+		t.Errorf("bitmask form: got %#v, want %#v", bitmask, want)
+	}
+
+	names, err := m([]interface{}{"bit0", "bit2"})
+	if err != nil {
+		t.Fatalf("array-of-names form: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf(`["bit0", "bit2"]: got %#v, want the same value as bitmask 5 (%#v)`, names, want)
+	}
+
+	// the bare-decimal-index spelling addresses the same bits as the "bitN" spelling
+	decimalNames, err := m([]interface{}{"0", "2"})
+	if err != nil {
+		t.Fatalf("array-of-names form (decimal): unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(decimalNames, want) {
+		t.Errorf(`["0", "2"]: got %#v, want the same value as bitmask 5 (%#v)`, decimalNames, want)
+	}
+
+	if _, err := m([]interface{}{"not-a-bit"}); err == nil {
+		t.Errorf("array-of-names form: expected an error for an unresolvable bit name")
+	}
+
+	if _, err := m([]interface{}{8}); err == nil {
+		t.Errorf("array-of-names form: expected an error for a non-string element")
+	}
+}
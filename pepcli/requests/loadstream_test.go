@@ -0,0 +1,97 @@
+package requests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/infobloxopen/themis/pdp"
+)
+
+func writeTempRequestFile(t *testing.T, ext, content string) string {
+	t.Helper()
+
+	f, err := ioutil.TempFile("", "requests-*."+ext)
+	if err != nil {
+		t.Fatalf("TempFile: %s", err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("WriteString: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	return f.Name()
+}
+
+func TestLoadStreamDeliversEveryRequest(t *testing.T) {
+	path := writeTempRequestFile(t, "json", `{"attributes": {}, "requests": [{"a": "x"}, {"a": "y"}]}`)
+
+	ch, err := LoadStream(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("LoadStream: %s", err)
+	}
+
+	n := 0
+	for lr := range ch {
+		if lr.Err != nil {
+			t.Errorf("unexpected error: %s", lr.Err)
+		}
+		n++
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestLoadStreamWithSchema(t *testing.T) {
+	path := writeTempRequestFile(t, "json", `{"attributes": {}, "requests": [{"d": "example.com"}]}`)
+
+	ch, err := LoadStream(context.Background(), path, 0, WithSchema(map[string]pdp.Type{"d": pdp.TypeDomain}))
+	if err != nil {
+		t.Fatalf("LoadStream: %s", err)
+	}
+
+	lr, ok := <-ch
+	if !ok {
+		t.Fatal("expected one request on the channel")
+	}
+	if lr.Err != nil {
+		t.Fatalf("expected the schema-declared domain type to marshal cleanly, got %s", lr.Err)
+	}
+}
+
+func TestLoadStreamCancelStopsFeederGoroutine(t *testing.T) {
+	// A request file with more records than anyone reads off the channel. If the feeder goroutine
+	// doesn't watch ctx.Done(), it blocks forever on the unbuffered channel send and leaks.
+	path := writeTempRequestFile(t, "json",
+		`{"attributes": {}, "requests": [{"a": "1"}, {"a": "2"}, {"a": "3"}, {"a": "4"}]}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := LoadStream(ctx, path, 0)
+	if err != nil {
+		t.Fatalf("LoadStream: %s", err)
+	}
+
+	if _, ok := <-ch; !ok {
+		t.Fatal("expected at least one request before cancelling")
+	}
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			// a second request racing the cancellation is fine; just keep draining until closed
+			for range ch {
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel was not closed within 2s of cancelling ctx--feeder goroutine leaked")
+	}
+}
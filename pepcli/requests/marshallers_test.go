@@ -0,0 +1,104 @@
+package requests
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/infobloxopen/go-trees/domain"
+	"github.com/infobloxopen/themis/pdp"
+)
+
+func TestListOfIntegersMarshaller(t *testing.T) {
+	got, err := listOfIntegersMarshaller([]interface{}{1, int64(2), 3.0, "4"})
+	if err != nil {
+		t.Fatalf("mixed int/int64/float/numeric-string elements: unexpected error: %s", err)
+	}
+	if want := pdp.MakeListOfIntegersValue([]int64{1, 2, 3, 4}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	if got, err := listOfIntegersMarshaller([]interface{}{}); err != nil {
+		t.Errorf("empty list: unexpected error: %s", err)
+	} else if want := pdp.MakeListOfIntegersValue([]int64{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("empty list: got %#v, want %#v", got, want)
+	}
+
+	if _, err := listOfIntegersMarshaller([]interface{}{"not-a-number"}); err == nil {
+		t.Error("expected an error for a non-numeric string element")
+	}
+	if _, err := listOfIntegersMarshaller("not-a-list"); err == nil {
+		t.Error("expected an error for a non-array value")
+	}
+}
+
+func TestListOfFloatsMarshaller(t *testing.T) {
+	got, err := listOfFloatsMarshaller([]interface{}{1, int64(2), 3.5, "4.5"})
+	if err != nil {
+		t.Fatalf("mixed int/int64/float/numeric-string elements: unexpected error: %s", err)
+	}
+	if want := pdp.MakeListOfFloatsValue([]float64{1, 2, 3.5, 4.5}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	if _, err := listOfFloatsMarshaller([]interface{}{"not-a-number"}); err == nil {
+		t.Error("expected an error for a non-numeric string element")
+	}
+	if _, err := listOfFloatsMarshaller("not-a-list"); err == nil {
+		t.Error("expected an error for a non-array value")
+	}
+}
+
+func TestListOfNetworksMarshaller(t *testing.T) {
+	_, n1, _ := net.ParseCIDR("10.0.0.0/8")
+	_, n2, _ := net.ParseCIDR("2001:db8::/32")
+
+	got, err := listOfNetworksMarshaller([]interface{}{"10.0.0.0/8", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("CIDR strings: unexpected error: %s", err)
+	}
+	if want := pdp.MakeListOfNetworksValue([]*net.IPNet{n1, n2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	if _, err := listOfNetworksMarshaller([]interface{}{"not-a-cidr"}); err == nil {
+		t.Error("expected an error for a malformed CIDR string")
+	}
+	if _, err := listOfNetworksMarshaller([]interface{}{42}); err == nil {
+		t.Error("expected an error for a non-string, non-net.IPNet element")
+	}
+}
+
+func TestListOfDomainsMarshaller(t *testing.T) {
+	d1, _ := domain.MakeNameFromString("example.com")
+	d2, _ := domain.MakeNameFromString("sub.example.org")
+
+	got, err := listOfDomainsMarshaller([]interface{}{"example.com", "sub.example.org"})
+	if err != nil {
+		t.Fatalf("domain strings: unexpected error: %s", err)
+	}
+	if want := pdp.MakeListOfDomainsValue([]domain.Name{d1, d2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	if _, err := listOfDomainsMarshaller([]interface{}{"not a domain!"}); err == nil {
+		t.Error("expected an error for a malformed domain name")
+	}
+	if _, err := listOfDomainsMarshaller([]interface{}{7}); err == nil {
+		t.Error("expected an error for a non-string element")
+	}
+}
+
+func TestBuiltinMarshallersRegisterAllListTypes(t *testing.T) {
+	for _, typ := range []pdp.Type{
+		pdp.TypeListOfStrings,
+		pdp.TypeListOfIntegers,
+		pdp.TypeListOfFloats,
+		pdp.TypeListOfNetworks,
+		pdp.TypeListOfDomains,
+	} {
+		if _, ok := builtinMarshallers[typ]; !ok {
+			t.Errorf("no builtin marshaller registered for %v", typ)
+		}
+	}
+}
@@ -1,19 +1,23 @@
-// Package requests provides loader for YAML formatted authorization requests file.
+// Package requests provides loader for YAML, JSON and CBOR formatted authorization requests file.
 package requests
 
 //go:generate bash -c "mkdir -p $GOPATH/src/github.com/infobloxopen/themis/pdp-service && protoc -I $GOPATH/src/github.com/infobloxopen/themis/proto/ $GOPATH/src/github.com/infobloxopen/themis/proto/service.proto --go_out=plugins=grpc:$GOPATH/src/github.com/infobloxopen/themis/pdp-service && ls $GOPATH/src/github.com/infobloxopen/themis/pdp-service"
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"gopkg.in/yaml.v2"
+	"io"
 	"io/ioutil"
 	"math"
 	"net"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/fxamacker/cbor"
 	"github.com/infobloxopen/go-trees/domain"
 	"github.com/infobloxopen/themis/pdp"
 	pb "github.com/infobloxopen/themis/pdp-service"
@@ -22,6 +26,7 @@ import (
 const (
 	YAML          = "yaml"
 	JSON          = "json"
+	CBOR          = "cbor"
 	MaxFloat64Int = 1 << 53 // 9,007,199,254,740,992--the maximum IEEE-754 double float integer is not a golang const
 )
 
@@ -30,9 +35,54 @@ type requests struct {
 	Requests   []map[string]interface{}
 }
 
-// Load reads given data--if it is a filepath that ends in a yaml or json extension and can be read,
-// the respective unmarshaler will be used; otherwise, the input is processed as raw JSON.
-func Load(data string, size uint32) ([]pb.Msg, error) {
+// defaultLoader is the Loader used by the package-level Load, LoadReader, LoadStream and
+// NewDecoder functions. It's preloaded with the built-in marshallers and type guesser, so existing
+// callers see no change in behavior. It isn't exported, so callers that need domain-specific types
+// create a Loader of their own with NewLoader and extend it with RegisterMarshaller.
+var defaultLoader = NewLoader()
+
+// Loader parses YAML, JSON and CBOR authorization request files into PDP request messages. The
+// zero value is not usable; create one with NewLoader so the built-in attribute marshallers and
+// type guesser are in place, then override or extend them with RegisterMarshaller and GuessType.
+type Loader struct {
+	marshallers map[pdp.Type]attributeMarshaller
+
+	// GuessType infers an attribute's pdp.Type when the request file's "attributes" section
+	// doesn't declare one for it. It defaults to guessType and can be replaced to recognize
+	// additional Go value shapes (e.g. a custom struct standing in for a domain-specific type).
+	GuessType func(value interface{}) (pdp.Type, error)
+}
+
+// NewLoader creates a Loader preloaded with the built-in attribute marshallers and type guesser.
+func NewLoader() *Loader {
+	l := &Loader{marshallers: make(map[pdp.Type]attributeMarshaller, len(builtinMarshallers))}
+	for t, m := range builtinMarshallers {
+		l.marshallers[t] = m
+	}
+	l.GuessType = guessType
+
+	return l
+}
+
+// RegisterMarshaller teaches the Loader how to marshal attribute values declared as type t,
+// overriding any built-in or previously registered marshaller for that type. This is the extension
+// point for domain-specific pdp.Type values that the package doesn't ship a marshaller for.
+func (l *Loader) RegisterMarshaller(t pdp.Type, m attributeMarshaller) {
+	l.marshallers[t] = m
+}
+
+// Load reads given data--if it is a filepath that ends in a yaml, json or cbor extension, the file is
+// read (an error reading it is returned) and the respective unmarshaler is used; otherwise, the
+// input is processed as raw JSON.
+// opts can include WithSchema to drive attribute types from an externally loaded schema instead of,
+// or in addition to, the file's own "attributes" section.
+func Load(data string, size uint32, opts ...Option) ([]pb.Msg, error) {
+	return defaultLoader.Load(data, size, opts...)
+}
+
+// Load works like the package-level Load but resolves attribute types and marshals values using l,
+// so types registered with l.RegisterMarshaller are recognized.
+func (l *Loader) Load(data string, size uint32, opts ...Option) ([]pb.Msg, error) {
 	in := &requests{}
 
 	switch strings.TrimLeft(strings.ToLower(filepath.Ext(data)), ".") {
@@ -56,6 +106,16 @@ func Load(data string, size uint32) ([]pb.Msg, error) {
 		if err != nil {
 			return nil, err
 		}
+	case CBOR:
+		b, err := ioutil.ReadFile(data)
+		if err != nil {
+			return nil, err
+		}
+
+		err = cbor.Unmarshal(b, in)
+		if err != nil {
+			return nil, err
+		}
 	default: // assuming JSON-formatted string
 		err := json.Unmarshal([]byte(data), in)
 
@@ -64,7 +124,72 @@ func Load(data string, size uint32) ([]pb.Msg, error) {
 		}
 	}
 
-	symbols := make(map[string]pdp.Type)
+	return in.marshal(l, opts...)
+}
+
+// LoadReader works like Load but reads the request data from an already open reader rather than a
+// file path, using format to pick the unmarshaler (one of YAML, JSON or CBOR). It lets callers that
+// already hold request data in memory or stream it from elsewhere (e.g. a CBOR-encoded gRPC-web
+// payload) skip the round trip through a temporary file.
+func LoadReader(r io.Reader, format string, size uint32, opts ...Option) ([]pb.Msg, error) {
+	return defaultLoader.LoadReader(r, format, size, opts...)
+}
+
+// LoadReader works like the package-level LoadReader but resolves attribute types and marshals
+// values using l, so types registered with l.RegisterMarshaller are recognized.
+func (l *Loader) LoadReader(r io.Reader, format string, size uint32, opts ...Option) ([]pb.Msg, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &requests{}
+	switch strings.ToLower(format) {
+	case YAML:
+		err = yaml.Unmarshal(b, in)
+	case JSON:
+		err = json.Unmarshal(b, in)
+	case CBOR:
+		err = cbor.Unmarshal(b, in)
+	default:
+		return nil, fmt.Errorf("unknown request format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return in.marshal(l, opts...)
+}
+
+// Option customizes a single Load/LoadReader call. See WithSchema.
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	schema map[string]pdp.Type
+}
+
+// WithSchema makes Load/LoadReader resolve attribute types from schema--as returned by
+// LoadSchema--falling back to it for any attribute the request file's own "attributes" section
+// doesn't declare. An explicit entry in the file always takes precedence over the schema, so a
+// single canonical schema can drive many request payloads that only need to override a few types.
+func WithSchema(schema map[string]pdp.Type) Option {
+	return func(o *loadOptions) {
+		o.schema = schema
+	}
+}
+
+// marshal converts the parsed requests and attribute type declarations into PDP request messages
+// ready to send over the wire, using l to resolve and marshal each attribute value.
+func (in *requests) marshal(l *Loader, opts ...Option) ([]pb.Msg, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	symbols := make(map[string]pdp.Type, len(o.schema)+len(in.Attributes))
+	for k, t := range o.schema {
+		symbols[k] = t
+	}
 	for k, v := range in.Attributes {
 		t, ok := pdp.BuiltinTypes[strings.ToLower(v)]
 		if !ok {
@@ -79,7 +204,7 @@ func Load(data string, size uint32) ([]pb.Msg, error) {
 		attrs := make([]pdp.AttributeAssignment, len(r))
 		j := 0
 		for k, v := range r {
-			a, err := makeAttribute(k, v, symbols)
+			a, err := l.makeAttribute(k, v, symbols)
 			if err != nil {
 				return nil, fmt.Errorf("invalid attribute in request %d: %s", i+1, err)
 			}
@@ -100,30 +225,353 @@ func Load(data string, size uint32) ([]pb.Msg, error) {
 	return out, nil
 }
 
+// LoadedRequest pairs a request decoded by LoadStream with any error hit while decoding or
+// marshalling it, so that a single malformed record doesn't abort the rest of the stream.
+type LoadedRequest struct {
+	Request pb.Msg
+	Err     error
+}
+
+// LoadStream opens path and streams its requests one at a time on the returned channel instead of
+// marshalling them all up front the way Load does. It's meant for feeding huge YAML/JSON
+// load-testing corpora through the PDP; a JSON file is decoded incrementally, while a YAML file is
+// still read into memory whole (see Decoder). size is unused for now, kept for parity with Load's
+// signature. opts can include WithSchema, same as Load/LoadReader. A malformed request is reported
+// on the channel as a LoadedRequest with Err set and does not stop the stream, except for a JSON
+// syntax error, which is reported once and ends it (see Decoder.Next); the channel is closed once
+// every request has been consumed or ctx is done, whichever comes first--cancel ctx to stop a
+// consumer that abandons the stream early so the goroutine feeding the channel (and the open file
+// underneath it) isn't leaked.
+func LoadStream(ctx context.Context, path string, size uint32, opts ...Option) (<-chan LoadedRequest, error) {
+	return defaultLoader.LoadStream(ctx, path, size, opts...)
+}
+
+// LoadStream works like the package-level LoadStream but resolves attribute types and marshals
+// values using l, so types registered with l.RegisterMarshaller are recognized.
+func (l *Loader) LoadStream(ctx context.Context, path string, size uint32, opts ...Option) (<-chan LoadedRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := strings.TrimLeft(strings.ToLower(filepath.Ext(path)), ".")
+	d, err := l.NewDecoder(f, format, opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	ch := make(chan LoadedRequest)
+	go func() {
+		defer f.Close()
+		defer close(ch)
+
+		for {
+			r, err := d.Next()
+			if err == io.EOF {
+				return
+			}
+
+			select {
+			case ch <- LoadedRequest{Request: r, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Decoder incrementally decodes, marshals and returns one authorization request at a time from a
+// YAML or JSON request document. For JSON, it reads the document as a stream of tokens and starts
+// handing back requests as soon as it reaches the "requests" array, without ever holding more than
+// one request in memory--this is the common case, since "attributes" naturally reads first in a
+// well-formed document. JSON object member order isn't guaranteed, though, so if "requests" arrives
+// before "attributes" in the source, the requests array is buffered (still as undecoded elements,
+// so marshalling each one is deferred to Next) while the scan continues looking for a later
+// "attributes" key; only that misordered case pays a memory cost. gopkg.in/yaml.v2 has no
+// token-level decoder at all, so YAML documents are always read into memory up front.
+type Decoder struct {
+	loader  *Loader
+	format  string
+	schema  map[string]pdp.Type
+	symbols map[string]pdp.Type
+	buf     []byte
+
+	jDec   *json.Decoder // non-nil while requests are still being streamed from the source
+	jReqs  []json.RawMessage
+	jFatal error
+	yReqs  []map[string]interface{}
+	idx    int
+}
+
+// NewDecoder creates a Decoder reading from r using the default loader. format selects the wire
+// encoding and must be one of YAML or JSON (CBOR requests are small enough in practice that
+// streaming them isn't supported). opts can include WithSchema, same as Load/LoadReader.
+func NewDecoder(r io.Reader, format string, opts ...Option) (*Decoder, error) {
+	return defaultLoader.NewDecoder(r, format, opts...)
+}
+
+// NewDecoder creates a Decoder reading from r, resolving attribute types and marshalling values
+// using l, so types registered with l.RegisterMarshaller are recognized by Next.
+func (l *Loader) NewDecoder(r io.Reader, format string, opts ...Option) (*Decoder, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	d := &Decoder{loader: l, format: strings.ToLower(format), schema: o.schema, buf: make([]byte, 10240)}
+
+	switch d.format {
+	case JSON:
+		if err := d.enterJSON(json.NewDecoder(r)); err != nil {
+			return nil, err
+		}
+	case YAML:
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		in := &requests{}
+		if err := yaml.Unmarshal(b, in); err != nil {
+			return nil, err
+		}
+
+		if err := d.setSymbols(in.Attributes); err != nil {
+			return nil, err
+		}
+
+		d.yReqs = in.Requests
+	default:
+		return nil, fmt.Errorf("unknown request stream format %q", format)
+	}
+
+	return d, nil
+}
+
+// enterJSON walks dec's top-level object up to the "requests" array, resolving "attributes" into
+// d.symbols along the way. When "attributes" precedes "requests"--the common case--dec is left
+// positioned inside the "requests" array for nextJSON to decode one element at a time, and this
+// never reads the array into memory. When "requests" arrives first, it's decoded in full (as
+// undecoded json.RawMessage elements--individual requests are still marshalled lazily by Next) so
+// the scan can keep looking for a later "attributes" key instead of guessing every attribute's type.
+func (d *Decoder) enterJSON(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, got %v", tok)
+	}
+
+	sawRequests := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected a string key, got %v", tok)
+		}
+
+		switch key {
+		case "attributes":
+			var attrs map[string]string
+			if err := dec.Decode(&attrs); err != nil {
+				return fmt.Errorf("can't decode \"attributes\": %s", err)
+			}
+			if err := d.setSymbols(attrs); err != nil {
+				return err
+			}
+		case "requests":
+			sawRequests = true
+			if d.symbols != nil {
+				tok, err := dec.Token()
+				if err != nil {
+					return fmt.Errorf("can't decode \"requests\": %s", err)
+				}
+				if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+					return fmt.Errorf("expected \"requests\" to be an array, got %v", tok)
+				}
+
+				d.jDec = dec
+				return nil
+			}
+
+			if err := dec.Decode(&d.jReqs); err != nil {
+				return fmt.Errorf("can't decode \"requests\": %s", err)
+			}
+		default:
+			var discard json.RawMessage
+			if err := dec.Decode(&discard); err != nil {
+				return fmt.Errorf("can't skip %q: %s", key, err)
+			}
+		}
+	}
+
+	if !sawRequests {
+		return fmt.Errorf("document has no \"requests\" array")
+	}
+	if d.symbols == nil {
+		return d.setSymbols(nil)
+	}
+
+	return nil
+}
+
+func (d *Decoder) setSymbols(attrs map[string]string) error {
+	symbols := make(map[string]pdp.Type, len(d.schema)+len(attrs))
+	for k, t := range d.schema {
+		symbols[k] = t
+	}
+	for k, v := range attrs {
+		t, ok := pdp.BuiltinTypes[strings.ToLower(v)]
+		if !ok {
+			return fmt.Errorf("unknown type %q of %q attribute", v, k)
+		}
+
+		symbols[k] = t
+	}
+
+	d.symbols = symbols
+	return nil
+}
+
+// Next decodes and marshals the next request in the stream. It returns io.EOF once every request
+// has been consumed. An error decoding or marshalling one request (a malformed record, an
+// attribute that doesn't match its declared type, ...) is specific to that request and doesn't
+// prevent a later call to Next from reaching the requests after it. The one exception is a JSON
+// syntax error while streaming straight from the source: encoding/json has no API to skip to the
+// next array element, so the shared token stream is left in an unknown position and can't safely
+// be read further--that's reported once as a fatal error, after which Next returns io.EOF like the
+// stream ended, rather than retrying the same bytes and the same error forever.
+func (d *Decoder) Next() (pb.Msg, error) {
+	switch d.format {
+	case JSON:
+		return d.nextJSON()
+	case YAML:
+		return d.nextYAML()
+	}
+
+	return pb.Msg{}, fmt.Errorf("unknown request stream format %q", d.format)
+}
+
+func (d *Decoder) nextJSON() (pb.Msg, error) {
+	if d.jFatal != nil {
+		return pb.Msg{}, io.EOF
+	}
+
+	if d.jDec != nil {
+		if !d.jDec.More() {
+			d.jDec = nil
+			return pb.Msg{}, io.EOF
+		}
+
+		var raw json.RawMessage
+		if err := d.jDec.Decode(&raw); err != nil {
+			d.jFatal = err
+			d.jDec = nil
+			return pb.Msg{}, fmt.Errorf("invalid request %d, can't continue streaming the rest: %s", d.idx+1, err)
+		}
+
+		d.idx++
+		return d.unmarshalAndMarshalOne(raw)
+	}
+
+	if d.idx >= len(d.jReqs) {
+		return pb.Msg{}, io.EOF
+	}
+
+	raw := d.jReqs[d.idx]
+	d.idx++
+
+	return d.unmarshalAndMarshalOne(raw)
+}
+
+func (d *Decoder) unmarshalAndMarshalOne(raw json.RawMessage) (pb.Msg, error) {
+	var r map[string]interface{}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return pb.Msg{}, fmt.Errorf("invalid request %d: %s", d.idx, err)
+	}
+
+	return d.marshalOne(r)
+}
+
+func (d *Decoder) nextYAML() (pb.Msg, error) {
+	if d.idx >= len(d.yReqs) {
+		return pb.Msg{}, io.EOF
+	}
+
+	r := d.yReqs[d.idx]
+	d.idx++
+
+	return d.marshalOne(r)
+}
+
+// marshalOne marshals a single decoded request record into a PDP request message, reusing d.buf
+// as scratch space instead of allocating a new 10 KiB buffer per call; only the marshalled bytes
+// are copied out into a new slice of their exact size.
+func (d *Decoder) marshalOne(r map[string]interface{}) (pb.Msg, error) {
+	attrs := make([]pdp.AttributeAssignment, len(r))
+	i := 0
+	for k, v := range r {
+		a, err := d.loader.makeAttribute(k, v, d.symbols)
+		if err != nil {
+			return pb.Msg{}, fmt.Errorf("invalid attribute: %s", err)
+		}
+
+		attrs[i] = a
+		i++
+	}
+
+	n, err := pdp.MarshalRequestAssignmentsToBuffer(d.buf, attrs)
+	if err != nil {
+		return pb.Msg{}, fmt.Errorf("can't create request: %s", err)
+	}
+
+	b := make([]byte, n)
+	copy(b, d.buf[:n])
+
+	return pb.Msg{Body: b}, nil
+}
+
 type attributeMarshaller func(value interface{}) (pdp.AttributeValue, error)
 
-var marshallers = map[pdp.Type]attributeMarshaller{
-	pdp.TypeBoolean:       booleanMarshaller,
-	pdp.TypeString:        stringMarshaller,
-	pdp.TypeInteger:       integerMarshaller,
-	pdp.TypeFloat:         floatMarshaller,
-	pdp.TypeAddress:       addressMarshaller,
-	pdp.TypeNetwork:       networkMarshaller,
-	pdp.TypeDomain:        domainMarshaller,
-	pdp.TypeListOfStrings: listOfStringsMarshaller}
-
-func makeAttribute(name string, value interface{}, symbols map[string]pdp.Type) (pdp.AttributeAssignment, error) {
+// builtinMarshallers seeds every new Loader. Register additional or overriding marshallers on a
+// Loader instance with RegisterMarshaller instead of editing this map.
+var builtinMarshallers = map[pdp.Type]attributeMarshaller{
+	pdp.TypeBoolean:        booleanMarshaller,
+	pdp.TypeString:         stringMarshaller,
+	pdp.TypeInteger:        integerMarshaller,
+	pdp.TypeFloat:          floatMarshaller,
+	pdp.TypeAddress:        addressMarshaller,
+	pdp.TypeNetwork:        networkMarshaller,
+	pdp.TypeDomain:         domainMarshaller,
+	pdp.TypeListOfStrings:  listOfStringsMarshaller,
+	pdp.TypeListOfIntegers: listOfIntegersMarshaller,
+	pdp.TypeListOfFloats:   listOfFloatsMarshaller,
+	pdp.TypeListOfNetworks: listOfNetworksMarshaller,
+	pdp.TypeListOfDomains:  listOfDomainsMarshaller,
+	pdp.TypeFlags8:         makeFlagsMarshaller(8, func(n uint64) pdp.AttributeValue { return pdp.MakeFlags8Value(uint8(n)) }),
+	pdp.TypeFlags32:        makeFlagsMarshaller(32, func(n uint64) pdp.AttributeValue { return pdp.MakeFlags32Value(uint32(n)) }),
+	pdp.TypeFlags64:        makeFlagsMarshaller(64, func(n uint64) pdp.AttributeValue { return pdp.MakeFlags64Value(n) }),
+}
+
+func (l *Loader) makeAttribute(name string, value interface{}, symbols map[string]pdp.Type) (pdp.AttributeAssignment, error) {
 	t, ok := symbols[name]
 	var err error
 	if !ok {
-		t, err = guessType(value)
+		t, err = l.GuessType(value)
 		if err != nil {
 			return pdp.AttributeAssignment{},
 				fmt.Errorf("type of %q attribute isn't defined and can't be derived: %s", name, err)
 		}
 	}
 
-	marshaller, ok := marshallers[t]
+	marshaller, ok := l.marshallers[t]
 	if !ok {
 		return pdp.AttributeAssignment{},
 			fmt.Errorf("marshaling hasn't been implemented for type %q of %q attribute", t, name)
@@ -138,6 +586,10 @@ func makeAttribute(name string, value interface{}, symbols map[string]pdp.Type)
 	return pdp.MakeExpressionAssignment(name, v), nil
 }
 
+// guessType is the default Loader.GuessType: it recognizes booleans, strings, net.IP and net.IPNet
+// values, and non-empty arrays whose first element is a string or a number. Scalar numbers, maps,
+// empty arrays and types that need more than a bare Go kind to disambiguate (e.g. flags) aren't
+// guessed and must be declared in the request file's "attributes" section.
 func guessType(value interface{}) (pdp.Type, error) {
 	switch value := value.(type) {
 	case bool:
@@ -154,9 +606,17 @@ func guessType(value interface{}) (pdp.Type, error) {
 		if len(value) == 0 {
 			return pdp.TypeUndefined, fmt.Errorf("unable to unmarshal empty array of unknown type %T", value)
 		}
-		switch value[0].(type) {
+		switch head := value[0].(type) {
 		case string:
 			return pdp.TypeListOfStrings, nil
+		case int, int64:
+			return pdp.TypeListOfIntegers, nil
+		case float64:
+			if head == math.Trunc(head) {
+				return pdp.TypeListOfIntegers, nil
+			}
+
+			return pdp.TypeListOfFloats, nil
 		}
 	}
 
@@ -325,3 +785,196 @@ func listOfStringsMarshaller(value interface{}) (pdp.AttributeValue, error) {
 
 	return pdp.MakeListOfStringsValue(los), nil
 }
+
+func listOfIntegersMarshaller(value interface{}) (pdp.AttributeValue, error) {
+	v, ok := value.([]interface{})
+	if !ok {
+		return pdp.UndefinedValue, fmt.Errorf("can't marshal %T as list of integers", value)
+	}
+	if len(v) == 0 {
+		return pdp.MakeListOfIntegersValue([]int64{}), nil
+	}
+
+	loi := make([]int64, 0, len(v))
+	for i, e := range v {
+		switch e := e.(type) {
+		case int:
+			loi = append(loi, int64(e))
+		case int64:
+			loi = append(loi, e)
+		case float64:
+			if e <= -MaxFloat64Int || e >= MaxFloat64Int {
+				return pdp.UndefinedValue, fmt.Errorf("can't marshal %g at %d as int64 in list of integers", e, i)
+			}
+
+			loi = append(loi, int64(e))
+		case string:
+			n, err := strconv.ParseInt(e, 10, 64)
+			if err != nil {
+				return pdp.UndefinedValue, fmt.Errorf("can't marshal %q at %d as int64 in list of integers", e, i)
+			}
+
+			loi = append(loi, n)
+		default:
+			return pdp.UndefinedValue, fmt.Errorf("can't marshal %T at %d as integer in list of integers", e, i)
+		}
+	}
+
+	return pdp.MakeListOfIntegersValue(loi), nil
+}
+
+func listOfFloatsMarshaller(value interface{}) (pdp.AttributeValue, error) {
+	v, ok := value.([]interface{})
+	if !ok {
+		return pdp.UndefinedValue, fmt.Errorf("can't marshal %T as list of floats", value)
+	}
+	if len(v) == 0 {
+		return pdp.MakeListOfFloatsValue([]float64{}), nil
+	}
+
+	lof := make([]float64, 0, len(v))
+	for i, e := range v {
+		switch e := e.(type) {
+		case int:
+			lof = append(lof, float64(e))
+		case int64:
+			lof = append(lof, float64(e))
+		case float64:
+			lof = append(lof, e)
+		case string:
+			f, err := strconv.ParseFloat(e, 64)
+			if err != nil {
+				return pdp.UndefinedValue, fmt.Errorf("can't marshal %q at %d as float64 in list of floats", e, i)
+			}
+
+			lof = append(lof, f)
+		default:
+			return pdp.UndefinedValue, fmt.Errorf("can't marshal %T at %d as float in list of floats", e, i)
+		}
+	}
+
+	return pdp.MakeListOfFloatsValue(lof), nil
+}
+
+func listOfNetworksMarshaller(value interface{}) (pdp.AttributeValue, error) {
+	v, ok := value.([]interface{})
+	if !ok {
+		return pdp.UndefinedValue, fmt.Errorf("can't marshal %T as list of networks", value)
+	}
+	if len(v) == 0 {
+		return pdp.MakeListOfNetworksValue([]*net.IPNet{}), nil
+	}
+
+	lon := make([]*net.IPNet, 0, len(v))
+	for i, e := range v {
+		switch e := e.(type) {
+		case net.IPNet:
+			n := e
+			lon = append(lon, &n)
+		case *net.IPNet:
+			lon = append(lon, e)
+		case string:
+			_, n, err := net.ParseCIDR(e)
+			if err != nil {
+				return pdp.UndefinedValue, fmt.Errorf("can't marshal %q at %d as network in list of networks", e, i)
+			}
+
+			lon = append(lon, n)
+		default:
+			return pdp.UndefinedValue, fmt.Errorf("can't marshal %T at %d as network in list of networks", e, i)
+		}
+	}
+
+	return pdp.MakeListOfNetworksValue(lon), nil
+}
+
+func listOfDomainsMarshaller(value interface{}) (pdp.AttributeValue, error) {
+	v, ok := value.([]interface{})
+	if !ok {
+		return pdp.UndefinedValue, fmt.Errorf("can't marshal %T as list of domains", value)
+	}
+	if len(v) == 0 {
+		return pdp.MakeListOfDomainsValue([]domain.Name{}), nil
+	}
+
+	lod := make([]domain.Name, 0, len(v))
+	for i, e := range v {
+		s, ok := e.(string)
+		if !ok {
+			return pdp.UndefinedValue, fmt.Errorf("can't marshal %T at %d as domain in list of domains", e, i)
+		}
+
+		d, err := domain.MakeNameFromString(s)
+		if err != nil {
+			return pdp.UndefinedValue, fmt.Errorf("can't marshal %q at %d as domain in list of domains: %s", s, i, err)
+		}
+
+		lod = append(lod, d)
+	}
+
+	return pdp.MakeListOfDomainsValue(lod), nil
+}
+
+// makeFlagsMarshaller builds a marshaller for an n-bit flags type, accepting either an integer
+// bitmask (as a number or a numeric string) or a list of set-bit names. newValue wraps the parsed
+// bitmask in the AttributeValue constructor for the concrete n-bit flags type.
+func makeFlagsMarshaller(bits int, newValue func(uint64) pdp.AttributeValue) attributeMarshaller {
+	return func(value interface{}) (pdp.AttributeValue, error) {
+		switch value := value.(type) {
+		case int:
+			return newValue(uint64(value)), nil
+		case int64:
+			return newValue(uint64(value)), nil
+		case uint64:
+			return newValue(value), nil
+		case float64:
+			return newValue(uint64(value)), nil
+		case string:
+			n, err := strconv.ParseUint(value, 0, bits)
+			if err != nil {
+				return pdp.UndefinedValue, fmt.Errorf("can't marshal %q as %d-bit flags bitmask", value, bits)
+			}
+
+			return newValue(n), nil
+		case []interface{}:
+			var mask uint64
+			for i, e := range value {
+				name, ok := e.(string)
+				if !ok {
+					return pdp.UndefinedValue, fmt.Errorf("can't marshal %T at %d as a flag bit name", e, i)
+				}
+
+				bit, err := flagBitFromName(name, bits)
+				if err != nil {
+					return pdp.UndefinedValue, fmt.Errorf("can't marshal %q at %d as a flag bit name: %s", name, i, err)
+				}
+
+				mask |= uint64(1) << uint(bit)
+			}
+
+			return newValue(mask), nil
+		}
+
+		return pdp.UndefinedValue, fmt.Errorf("can't marshal %T as %d-bit flags", value, bits)
+	}
+}
+
+// flagBitFromName resolves a set-bit name used in the array form of the built-in flags types to
+// its zero-based bit position. The built-in Flags8/32/64 types have no domain-specific name table
+// of their own, so a name is either a bare decimal bit index ("5") or that index prefixed with
+// "bit" (case-insensitive, e.g. "bit5"); both forms address bit 5, i.e. the 0x20 mask. Callers that
+// want real semantic bit names for their own flags type should RegisterMarshaller a marshaller
+// bound to it instead of relying on this generic naming.
+func flagBitFromName(name string, bits int) (int, error) {
+	s := strings.TrimPrefix(strings.ToLower(name), "bit")
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("expected a bit index or \"bitN\", got %q", name)
+	}
+	if n < 0 || n >= bits {
+		return 0, fmt.Errorf("bit index %d is out of range for %d-bit flags", n, bits)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,251 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/emicklei/proto"
+
+	"github.com/infobloxopen/themis/pdp"
+)
+
+// LoadSchema reads an external attribute-type schema and returns it as the same
+// map[string]pdp.Type shape a request file's own "attributes" section produces, for use with
+// WithSchema. The schema format is picked from path's extension: ".proto" for a protobuf message
+// descriptor, ".json" for a JSON-schema document, ".yaml"/".yml" for an OpenAPI document--a single
+// "components.schemas" entry is read from it, named by a "#<name>" fragment appended to path (e.g.
+// "api.yaml#Request"); the fragment can be omitted if the document has exactly one schema. This
+// lets a single canonical schema drive many request payloads instead of hand-maintaining the
+// "attributes:" section in every one of them.
+func LoadSchema(path string) (map[string]pdp.Type, error) {
+	path, name := splitSchemaFragment(path)
+
+	ext := strings.TrimLeft(strings.ToLower(filepath.Ext(path)), ".")
+	switch ext {
+	case "proto", JSON, YAML, "yml":
+	default:
+		return nil, fmt.Errorf("unsupported schema file extension %q", filepath.Ext(path))
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ext {
+	case "proto":
+		return protoSchema(b)
+	case JSON:
+		return jsonSchema(b)
+	case YAML, "yml":
+		return openAPISchema(b, name)
+	}
+
+	return nil, fmt.Errorf("unsupported schema file extension %q", filepath.Ext(path))
+}
+
+// splitSchemaFragment splits a "#<name>" suffix--identifying a single schema within an OpenAPI
+// document's components.schemas--off of path. It returns an empty name when path has no fragment.
+func splitSchemaFragment(path string) (file, name string) {
+	if i := strings.IndexByte(path, '#'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+
+	return path, ""
+}
+
+// protoSchema maps the fields of the first message in a .proto file to pdp.Type, using the
+// protobuf scalar/well-known type mapping in protoFieldType.
+func protoSchema(b []byte) (map[string]pdp.Type, error) {
+	def, err := proto.NewParser(strings.NewReader(string(b))).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg *proto.Message
+	proto.Walk(def, proto.WithMessage(func(m *proto.Message) {
+		if msg == nil {
+			msg = m
+		}
+	}))
+	if msg == nil {
+		return nil, fmt.Errorf("no message definition found")
+	}
+
+	out := make(map[string]pdp.Type)
+	for _, e := range msg.Elements {
+		f, ok := e.(*proto.NormalField)
+		if !ok {
+			continue
+		}
+
+		t, err := protoFieldType(f.Type, f.Repeated)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %s", f.Name, err)
+		}
+
+		out[f.Name] = t
+	}
+
+	return out, nil
+}
+
+func protoFieldType(typ string, repeated bool) (pdp.Type, error) {
+	switch typ {
+	case "IPAddress":
+		return pdp.TypeAddress, nil
+	case "IPPrefix":
+		return pdp.TypeNetwork, nil
+	}
+
+	if repeated {
+		switch typ {
+		case "string":
+			return pdp.TypeListOfStrings, nil
+		case "int32", "int64", "uint32", "uint64":
+			return pdp.TypeListOfIntegers, nil
+		case "double", "float":
+			return pdp.TypeListOfFloats, nil
+		}
+
+		return pdp.TypeUndefined, fmt.Errorf("repeated %q has no list-of-X counterpart", typ)
+	}
+
+	switch typ {
+	case "string":
+		return pdp.TypeString, nil
+	case "bool":
+		return pdp.TypeBoolean, nil
+	case "int32", "int64", "uint32", "uint64":
+		return pdp.TypeInteger, nil
+	case "double", "float":
+		return pdp.TypeFloat, nil
+	}
+
+	return pdp.TypeUndefined, fmt.Errorf("unsupported protobuf type %q", typ)
+}
+
+// jsonSchemaProperty is the subset of JSON-schema (and, since OpenAPI schema objects reuse the
+// same vocabulary, OpenAPI) that's needed to pick a pdp.Type.
+type jsonSchemaProperty struct {
+	Type   string              `json:"type" yaml:"type"`
+	Format string              `json:"format" yaml:"format"`
+	Items  *jsonSchemaProperty `json:"items" yaml:"items"`
+}
+
+type jsonSchemaDoc struct {
+	Properties map[string]jsonSchemaProperty `json:"properties"`
+}
+
+func jsonSchema(b []byte) (map[string]pdp.Type, error) {
+	var doc jsonSchemaDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	return propertiesToTypes(doc.Properties)
+}
+
+type openAPIDoc struct {
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]jsonSchemaProperty `yaml:"properties"`
+		} `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// openAPISchema maps the properties of a single entry of an OpenAPI document's
+// components.schemas to pdp.Type. name picks the entry; if it's empty, the document must have
+// exactly one entry, since blending properties from unrelated schemas together would make the
+// resolved type for a property name that happens to collide between them depend on Go's
+// randomized map iteration order.
+func openAPISchema(b []byte, name string) (map[string]pdp.Type, error) {
+	var doc openAPIDoc
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		s, ok := doc.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("no %q schema in components.schemas", name)
+		}
+
+		return propertiesToTypes(s.Properties)
+	}
+
+	if len(doc.Components.Schemas) != 1 {
+		names := make([]string, 0, len(doc.Components.Schemas))
+		for k := range doc.Components.Schemas {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf(
+			"document has %d schemas in components.schemas (%s); pick one with a \"#<name>\" fragment on the path",
+			len(doc.Components.Schemas), strings.Join(names, ", "))
+	}
+
+	for _, s := range doc.Components.Schemas {
+		return propertiesToTypes(s.Properties)
+	}
+
+	return nil, fmt.Errorf("no schemas found in components.schemas")
+}
+
+func propertiesToTypes(props map[string]jsonSchemaProperty) (map[string]pdp.Type, error) {
+	out := make(map[string]pdp.Type, len(props))
+	for name, p := range props {
+		t, err := jsonSchemaPropertyType(p)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %s", name, err)
+		}
+
+		out[name] = t
+	}
+
+	return out, nil
+}
+
+func jsonSchemaPropertyType(p jsonSchemaProperty) (pdp.Type, error) {
+	switch p.Type {
+	case "string":
+		switch p.Format {
+		case "ipv4", "ipv6":
+			return pdp.TypeAddress, nil
+		case "hostname":
+			return pdp.TypeDomain, nil
+		}
+
+		return pdp.TypeString, nil
+	case "boolean":
+		return pdp.TypeBoolean, nil
+	case "integer":
+		return pdp.TypeInteger, nil
+	case "number":
+		return pdp.TypeFloat, nil
+	case "array":
+		if p.Items == nil {
+			return pdp.TypeUndefined, fmt.Errorf("array has no \"items\" schema")
+		}
+
+		switch p.Items.Type {
+		case "string":
+			return pdp.TypeListOfStrings, nil
+		case "integer":
+			return pdp.TypeListOfIntegers, nil
+		case "number":
+			return pdp.TypeListOfFloats, nil
+		}
+
+		return pdp.TypeUndefined, fmt.Errorf("array of %q has no list-of-X counterpart", p.Items.Type)
+	}
+
+	return pdp.TypeUndefined, fmt.Errorf("unsupported JSON-schema type %q", p.Type)
+}
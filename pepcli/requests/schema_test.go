@@ -0,0 +1,65 @@
+package requests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/infobloxopen/themis/pdp"
+)
+
+const multiSchemaDoc = `
+components:
+  schemas:
+    Request:
+      properties:
+        user:
+          type: string
+    Error:
+      properties:
+        user:
+          type: integer
+`
+
+func TestOpenAPISchemaRequiresDisambiguation(t *testing.T) {
+	if _, err := openAPISchema([]byte(multiSchemaDoc), ""); err == nil {
+		t.Fatal("expected an error when components.schemas has more than one entry and no fragment was given")
+	}
+}
+
+func TestOpenAPISchemaFragmentSelectsOneEntry(t *testing.T) {
+	types, err := openAPISchema([]byte(multiSchemaDoc), "Request")
+	if err != nil {
+		t.Fatalf("openAPISchema: %s", err)
+	}
+
+	if typ, ok := types["user"]; !ok || typ != pdp.TypeString {
+		t.Fatalf("expected Request.user to resolve to TypeString, got %v (declared: %v)", typ, ok)
+	}
+
+	types, err = openAPISchema([]byte(multiSchemaDoc), "Error")
+	if err != nil {
+		t.Fatalf("openAPISchema: %s", err)
+	}
+
+	if typ, ok := types["user"]; !ok || typ != pdp.TypeInteger {
+		t.Fatalf("expected Error.user to resolve to TypeInteger, got %v (declared: %v)", typ, ok)
+	}
+}
+
+func TestSplitSchemaFragment(t *testing.T) {
+	file, name := splitSchemaFragment("api.yaml#Request")
+	if file != "api.yaml" || name != "Request" {
+		t.Fatalf("splitSchemaFragment(%q) = (%q, %q)", "api.yaml#Request", file, name)
+	}
+
+	file, name = splitSchemaFragment("api.yaml")
+	if file != "api.yaml" || name != "" {
+		t.Fatalf("splitSchemaFragment(%q) = (%q, %q), want no fragment", "api.yaml", file, name)
+	}
+}
+
+func TestLoadSchemaUnsupportedExtension(t *testing.T) {
+	if _, err := LoadSchema("schema.txt"); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Fatalf("expected an \"unsupported\" error for an unknown schema extension, got %v", err)
+	}
+}